@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// onlinePlayer is what /api/players reports for someone currently connected.
+type onlinePlayer struct {
+	Name          string    `json:"name"`
+	SessionLength string    `json:"session_length"`
+	Since         time.Time `json:"since"`
+}
+
+// lastActivity returns the most recent since time across every user we know
+// about, online or not. It's used as the Last-Modified time for endpoints
+// that summarize the live user lists, since that's the last moment either
+// list could have changed.
+func lastActivity(usersOn userList, usersOff userList) time.Time {
+	var latest time.Time
+	for _, u := range usersOn {
+		if u.since.After(latest) {
+			latest = u.since
+		}
+	}
+	for _, u := range usersOff {
+		if u.since.After(latest) {
+			latest = u.since
+		}
+	}
+	return latest
+}
+
+// notModified checks the request's If-Modified-Since header against
+// modified, writing a 304 and returning true if the client's copy is
+// already current. Otherwise it sets Last-Modified on the response and
+// returns false so the caller can write the body.
+func notModified(w http.ResponseWriter, req *http.Request, modified time.Time) bool {
+	if modified.IsZero() {
+		return false
+	}
+	modified = modified.Truncate(time.Second)
+
+	if ims := req.Header.Get("If-Modified-Since"); ims != "" {
+		if t, err := http.ParseTime(ims); err == nil && !modified.After(t) {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+
+	w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	return false
+}
+
+// apiPlayersHandler serves GET /api/players: everyone currently online,
+// with how long they've been on.
+func apiPlayersHandler(w http.ResponseWriter, req *http.Request) {
+	usersMu.RLock()
+	modified := lastActivity(usersOn, usersOff)
+	onlineNow := make([]mcuser, 0, len(usersOn))
+	for _, user := range usersOn {
+		onlineNow = append(onlineNow, user)
+	}
+	usersMu.RUnlock()
+
+	if notModified(w, req, modified) {
+		return
+	}
+
+	players := make([]onlinePlayer, 0, len(onlineNow))
+	for _, user := range onlineNow {
+		howLong, _ := getHowLong(user.since)
+		players = append(players, onlinePlayer{
+			Name:          user.name,
+			SessionLength: howLong,
+			Since:         user.since,
+		})
+	}
+
+	writeJSON(w, players)
+}
+
+// apiPlayerHandler serves GET /api/players/{name}: lifetime stats for a
+// single player, pulled from the persistent store.
+func apiPlayerHandler(w http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(req.URL.Path, "/api/players/")
+	if name == "" {
+		http.NotFound(w, req)
+		return
+	}
+
+	if playerStore == nil {
+		http.Error(w, "player history isn't enabled (pass -store-path)", http.StatusServiceUnavailable)
+		return
+	}
+
+	stats, err := playerStore.PlayerStats(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if notModified(w, req, stats.LastSeen) {
+		return
+	}
+
+	writeJSON(w, stats)
+}
+
+// apiHistoryHandler serves GET /api/history?since=<RFC3339 timestamp>: the
+// raw login/logout event log, oldest first.
+func apiHistoryHandler(w http.ResponseWriter, req *http.Request) {
+	if playerStore == nil {
+		http.Error(w, "player history isn't enabled (pass -store-path)", http.StatusServiceUnavailable)
+		return
+	}
+
+	var since time.Time
+	if raw := req.URL.Query().Get("since"); raw != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, raw)
+		if err != nil {
+			http.Error(w, "since must be an RFC3339 timestamp", http.StatusBadRequest)
+			return
+		}
+	}
+
+	events, err := playerStore.History(since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, events)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -34,11 +34,18 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/howeyc/fsnotify"
+	"github.com/trapgate/mcwho/parser"
+	"github.com/trapgate/mcwho/rcon"
+	"github.com/trapgate/mcwho/script"
+	"github.com/trapgate/mcwho/store"
 )
 
 type mcuser struct {
@@ -48,12 +55,54 @@ type mcuser struct {
 
 type userList map[string]mcuser
 
-// @@TODO: Lock these, or copy them to the rss goroutine
+// usersMu guards usersOn and usersOff, which are read and written from the
+// main loop, the rcon-poll goroutine, and the HTTP handlers in rssServer and
+// api.go.
+var usersMu sync.RWMutex
 var usersOn userList
 var usersOff userList
 
 // Command-line flags
 var logpath = flag.String("log-path", "logs/", "the location of the Minecraft server.log files")
+var rconAddr = flag.String("rcon-addr", "", "host:port of the Minecraft server's rcon listener (disabled if empty)")
+var rconPassword = flag.String("rcon-password", "", "password for the rcon listener")
+var rconPoll = flag.Duration("rcon-poll", 30*time.Second, "how often to poll rcon's \"list\" command to cross-check the log tailer")
+var scriptsDir = flag.String("scripts", "", "directory of Lua scripts to load and hot-reload (disabled if empty)")
+var storePath = flag.String("store-path", "mcwho.db", "where to persist player history (disabled if empty)")
+var pollInterval = flag.Duration("poll-interval", 5*time.Second, "fallback interval to poll the log directory in case fsnotify misses a rotation event (0 disables)")
+
+// rconClient is nil when -rcon-addr isn't set, which is the common case for
+// people who haven't turned rcon on in their server.properties.
+var rconClient *rcon.Client
+
+// scriptEngine is nil when -scripts isn't set.
+var scriptEngine *script.Engine
+
+// playerStore is nil when -store-path is set to "".
+var playerStore *store.Store
+
+// mcPlayers adapts the usersOn map to script.PlayerInfo.
+type mcPlayers struct{}
+
+func (mcPlayers) Players() []string {
+	usersMu.RLock()
+	defer usersMu.RUnlock()
+	names := make([]string, 0, len(usersOn))
+	for name := range usersOn {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (mcPlayers) Playtime(name string) time.Duration {
+	usersMu.RLock()
+	defer usersMu.RUnlock()
+	user, ok := usersOn[name]
+	if !ok {
+		return 0
+	}
+	return time.Since(user.since)
+}
 
 func main() {
 	flag.Parse()
@@ -62,32 +111,89 @@ func main() {
 	usersOn = make(userList)
 	usersOff = make(userList)
 
-	// Channels to communicate with the goroutine that watches the minecraft logfile:
-	conch := make(chan mcuser)
-	disch := make(chan mcuser)
+	// Channel to communicate with the goroutine that watches the minecraft logfile:
+	evch := make(chan parser.Event)
 	errch := make(chan error)
 
-	var user mcuser
+	// Open the persistent store, if enabled.
+	if *storePath != "" {
+		var err error
+		playerStore, err = store.Open(*storePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer playerStore.Close()
+	}
 
 	// Start up our RSS server
 	go startRssServer()
 
+	// If we've been given rcon credentials, connect and start polling "list"
+	// to catch anything the log tailer missed (crashes, log rotation, etc).
+	if *rconAddr != "" {
+		var err error
+		rconClient, err = rcon.Dial(*rconAddr, *rconPassword)
+		if err != nil {
+			log.Printf("rcon: couldn't connect to %s: %s", *rconAddr, err)
+		} else {
+			go pollRcon(*rconPoll, evch)
+		}
+	}
+
+	// Load any Lua scripts. Hot-reloading them happens inline in Mcwho's
+	// watcher loop below, via handleScriptEvent, rather than a second
+	// fsnotify watcher on the scripts directory.
+	if *scriptsDir != "" {
+		var cmdr script.Commander
+		if rconClient != nil {
+			cmdr = rconClient
+		}
+
+		var err error
+		scriptEngine, err = script.NewEngine(*scriptsDir, cmdr, mcPlayers{})
+		if err != nil {
+			log.Printf("script: couldn't load %s: %s", *scriptsDir, err)
+		}
+	}
+
 	// Now start up the logfile watcher
-	go Mcwho(*logpath, conch, disch, errch)
+	go Mcwho(*logpath, *scriptsDir, evch, errch)
 	for {
 		select {
-		case user = <-conch:
-			delete(usersOff, user.name)
-			usersOn[user.name] = user
-		case user = <-disch:
-			delete(usersOn, user.name)
-			usersOff[user.name] = user
+		case ev := <-evch:
+			handleEvent(ev)
 		case err := <-errch:
 			log.Fatal(err)
 		}
 	}
 }
 
+// handleEvent updates usersOn/usersOff for logins and logouts, persists
+// them to the store, and hands every event (including the ones that don't
+// affect who's online, like chat or deaths) to the script engine.
+func handleEvent(ev parser.Event) {
+	switch ev.Kind {
+	case parser.Login:
+		user := mcuser{ev.Name, ev.Time}
+		usersMu.Lock()
+		delete(usersOff, user.name)
+		usersOn[user.name] = user
+		usersMu.Unlock()
+		dispatchScriptEvent("login", ev)
+		recordStoreEvent(true, user)
+	case parser.Logout:
+		user := mcuser{ev.Name, ev.Time}
+		usersMu.Lock()
+		delete(usersOn, user.name)
+		usersOff[user.name] = user
+		usersMu.Unlock()
+		dispatchScriptEvent("logout", ev)
+		recordStoreEvent(false, user)
+	default:
+		dispatchScriptEvent(ev.Kind.String(), ev)
+	}
+}
+
 //
 // From the list of users, make the strings for display. The returned string
 // is of the format, "3 players: happy on for 1h, dopey on for 32s, lucky on
@@ -135,6 +241,9 @@ func getDisplay(usersOn userList, usersOff userList) string {
 //
 func startRssServer() {
 	http.HandleFunc("/mcwhorss", rssServer)
+	http.HandleFunc("/api/players", apiPlayersHandler)
+	http.HandleFunc("/api/players/", apiPlayerHandler)
+	http.HandleFunc("/api/history", apiHistoryHandler)
 	err := http.ListenAndServe(":9092", nil)
 	if err != nil {
 		fmt.Println("failed to start rss server")
@@ -162,8 +271,16 @@ func rssServer(w http.ResponseWriter, req *http.Request) {
 </channel>
 </rss>
 `
-	t, _ := template.New("feed").Parse(templateStr)
+	usersMu.RLock()
+	modified := lastActivity(usersOn, usersOff)
 	display := getDisplay(usersOn, usersOff)
+	usersMu.RUnlock()
+
+	if notModified(w, req, modified) {
+		return
+	}
+
+	t, _ := template.New("feed").Parse(templateStr)
 	fmt.Printf("RSS responds %s\n", display)
 	io.WriteString(w, xmlHdr)
 	err := t.ExecuteTemplate(w, "feed", display)
@@ -172,68 +289,213 @@ func rssServer(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// SendCommand issues cmd over rcon and returns the server's response. It's
+// used by features built on top of the log tailer (kick, say, msg) that need
+// to actually talk to the server rather than just observe it. It returns an
+// error if -rcon-addr wasn't given on the command line.
+func SendCommand(cmd string) (string, error) {
+	if rconClient == nil {
+		return "", fmt.Errorf("rcon is not configured; pass -rcon-addr to enable it")
+	}
+	return rconClient.SendCommand(cmd)
+}
+
+// pollRcon periodically issues the "list" command over rcon and reconciles
+// the result against usersOn/usersOff. The log tailer is our primary source
+// of login/logout events, but it can miss lines across a server crash or a
+// log rotation that happens mid-gzip, so rcon's answer is treated as
+// authoritative: anyone it says is online but we think is off gets logged
+// in (with an unknown since-time, set to now), and vice versa.
+func pollRcon(interval time.Duration, events chan parser.Event) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		reply, err := rconClient.SendCommand("list")
+		if err != nil {
+			log.Printf("rcon: list failed: %s", err)
+			continue
+		}
+
+		online, ok := rcon.ParseList(reply)
+		if !ok {
+			log.Printf("rcon: couldn't parse list reply %q", reply)
+			continue
+		}
+
+		onlineSet := make(map[string]bool, len(online))
+		for _, name := range online {
+			onlineSet[name] = true
+		}
+
+		// usersOn is also read and written from the main loop and from
+		// api.go's HTTP handlers, so snapshot the names we need to act on
+		// under the lock and do the actual event sends (which can block)
+		// after releasing it.
+		usersMu.RLock()
+		var missedLogins, missedLogouts []string
+		for name := range onlineSet {
+			if _, isOn := usersOn[name]; !isOn {
+				missedLogins = append(missedLogins, name)
+			}
+		}
+		for name := range usersOn {
+			if !onlineSet[name] {
+				missedLogouts = append(missedLogouts, name)
+			}
+		}
+		usersMu.RUnlock()
+
+		for _, name := range missedLogins {
+			log.Printf("rcon: %s is online but the log tailer missed their login", name)
+			events <- parser.Event{Kind: parser.Login, Name: name, Time: time.Now()}
+		}
+		for _, name := range missedLogouts {
+			log.Printf("rcon: %s is offline but the log tailer missed their logout", name)
+			events <- parser.Event{Kind: parser.Logout, Name: name, Time: time.Now()}
+		}
+	}
+}
+
+// recordStoreEvent persists a login (up) or logout (!up) event, if a store
+// is configured. It's a no-op otherwise so callers don't need to check.
+func recordStoreEvent(up bool, user mcuser) {
+	if playerStore == nil {
+		return
+	}
+
+	var err error
+	if up {
+		err = playerStore.RecordLogin(user.name, user.since)
+	} else {
+		err = playerStore.RecordLogout(user.name, user.since)
+	}
+	if err != nil {
+		log.Printf("store: failed to record event for %s: %s", user.name, err)
+	}
+}
+
+// dispatchScriptEvent hands an event to the script engine, if one is
+// loaded. It's a no-op otherwise so callers don't need to check.
+func dispatchScriptEvent(kind string, ev parser.Event) {
+	if scriptEngine == nil {
+		return
+	}
+	scriptEngine.Dispatch(script.Event{
+		Kind:  kind,
+		Name:  ev.Name,
+		Time:  ev.Time,
+		Line:  ev.Line,
+		Extra: ev.Message,
+	})
+}
+
 // Mcwho is a goroutine that parses and then watches a minecraft server.log file
-// to determine who is connected.
-func Mcwho(logPath string, conch chan mcuser, disch chan mcuser, errch chan error) {
+// to determine who is connected. See tailer.go for how it copes with log
+// rotation. If scriptsDir is set, the same watcher also hot-reloads Lua
+// scripts as they change, so the script engine doesn't need a watcher of
+// its own.
+func Mcwho(logPath, scriptsDir string, events chan parser.Event, errch chan error) {
 	// Close the channel on exit so the program terminates.
-	defer close(conch)
-	watcher, err := setupWatcher(path.Join(logPath, "latest.log"))
+	defer close(events)
+
+	// Watch the directory, not just latest.log: rotation replaces latest.log
+	// with a brand new inode, and we need the Rename/Remove event on the old
+	// one as well as the eventual Create of the new one.
+	watcher, err := setupWatcher(logPath, scriptsDir)
 	if err != nil {
 		errch <- err
 		return
 	}
 	defer watcher.Close()
 
-	// Get a list of all the log files in the directory. We'll read them all once
-	// and then watch the latest one for changes.
-	files, err := ioutil.ReadDir(logPath)
+	// Catch up on anything that's already been rotated and gzipped, e.g.
+	// from downtime since the last run. This is the only unscoped,
+	// directory-wide scan: everything it covers is older than startup, so
+	// from here on rotationCutoff lets the loop below look only at the one
+	// new .gz a rotation produces.
+	if err := ingestRotatedLogs(logPath, events); err != nil {
+		errch <- err
+		return
+	}
+	rotationCutoff := time.Now()
+
+	latest := path.Join(logPath, "latest.log")
+	lf, err := openLogFile(latest)
 	if err != nil {
 		errch <- err
 		return
 	}
-	for i := range files {
-		file := files[i]
-		if path.Ext(file.Name()) != gzipext {
-			// only look at .gz files here
-			continue
-		}
-		logFile := path.Join(logPath, file.Name())
-		fmt.Println("reading log", logFile)
-		err := readLog(logFile, conch, disch)
-		if err != nil {
-			fmt.Printf("Error reading log file %s: %s\n", logFile, err)
-			errch <- err
-			return
-		}
+	defer lf.Close()
+
+	// Fall back to polling Stat() on an interval in case fsnotify drops an
+	// event, which it's known to do under heavy inotify load. A nil channel
+	// in a select never fires, so -poll-interval=0 just disables this.
+	var pollc <-chan time.Time
+	if *pollInterval > 0 {
+		ticker := time.NewTicker(*pollInterval)
+		defer ticker.Stop()
+		pollc = ticker.C
 	}
 
-	logFile := path.Join(logPath, "latest.log")
-
 	for {
-		err := readLog(logFile, conch, disch)
-		if err != nil {
+		if err := lf.drain(events); err != nil {
 			errch <- err
 			return
 		}
 
+		if lf.rotated() {
+			// The fd we're holding stays valid even though its name now
+			// points to something else (or nothing), so one more drain
+			// picks up anything written right up to the rename.
+			lf.drain(events)
+			cutoff, err := ingestNewRotatedLog(logPath, rotationCutoff, lf.offset, events)
+			if err != nil {
+				log.Printf("mcwho: error ingesting rotated log: %s", err)
+			} else {
+				rotationCutoff = cutoff
+			}
+			if err := lf.reopen(); err != nil {
+				// latest.log may not exist yet if we're racing the
+				// rotation; the next tick will try again.
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
 		select {
-		case /*ev :=*/ <-watcher.Event:
-			// naught to do but loop again
+		case ev := <-watcher.Event:
+			if strings.HasSuffix(ev.Name, ".lua") {
+				handleScriptEvent(ev)
+				continue
+			}
+			if !strings.HasSuffix(ev.Name, "latest.log") {
+				continue
+			}
+			// Loop around: rotated()/drain() above will notice whatever
+			// this event was about.
 		case err := <-watcher.Error:
 			errch <- err
-			break
+			return
+		case <-pollc:
+			// Same as above: just give rotated()/drain() another pass.
 		}
 	}
 }
 
 //
-// Setup our fsnotify thingy so we know when the logfile gets updated.
+// Setup our fsnotify thingy so we know when something in logPath changes.
+// If scriptsDir is non-empty, the same watcher also covers it so hot-reload
+// events for *.lua files arrive on this same watcher.
 //
-func setupWatcher(logPath string) (*fsnotify.Watcher, error) {
+func setupWatcher(logPath, scriptsDir string) (*fsnotify.Watcher, error) {
 	watcher, err := fsnotify.NewWatcher()
 	if err == nil {
 		err = watcher.Watch(logPath)
 	}
+	if err == nil && scriptsDir != "" {
+		err = watcher.Watch(scriptsDir)
+	}
 
 	if err != nil {
 		log.Fatal(err)
@@ -242,108 +504,236 @@ func setupWatcher(logPath string) (*fsnotify.Watcher, error) {
 	return watcher, nil
 }
 
-var pos int64 // Keep track of how far we've read.
+// handleScriptEvent reacts to an fsnotify event on a *.lua file in
+// -scripts, reloading or unloading the affected script. It reuses the
+// watcher Mcwho already has open rather than giving the script engine a
+// second one.
+func handleScriptEvent(ev *fsnotify.FileEvent) {
+	if scriptEngine == nil {
+		return
+	}
+	if ev.IsDelete() || ev.IsRename() {
+		scriptEngine.Unload(filepath.Clean(ev.Name))
+		return
+	}
+	if err := scriptEngine.Load(filepath.Clean(ev.Name)); err != nil {
+		log.Printf("script: failed to reload %s: %s", ev.Name, err)
+	}
+}
+
 const gzipext = ".gz"
 
-func getLogReader(logfile string, logf *os.File) (*bufio.Reader, error) {
-	var rdr *bufio.Reader
-	// Is this a gzip file?
-	if path.Ext(logfile) == gzipext {
-		gzrdr, err := gzip.NewReader(logf)
-		if err != nil {
-			return nil, err
+// ingestRotatedLogs reads every .gz log in logPath that we haven't already
+// recorded, sending the events it finds to events. It's idempotent (via the
+// store's per-file size bookkeeping, when a store is configured) so calling
+// it again after a rotation, or on the next process restart, doesn't
+// double-count anything.
+func ingestRotatedLogs(logPath string, events chan parser.Event) error {
+	files, err := ioutil.ReadDir(logPath)
+	if err != nil {
+		return err
+	}
+
+	for i := range files {
+		file := files[i]
+		if path.Ext(file.Name()) != gzipext {
+			// only look at .gz files here
+			continue
 		}
-		rdr = bufio.NewReader(gzrdr)
-	} else {
-		// See if the file has shrunk. If so, read from the beginning.
-		fi, err := logf.Stat()
-		if err != nil {
-			return nil, err
+		logFile := path.Join(logPath, file.Name())
+
+		if playerStore != nil {
+			done, err := playerStore.IsFileProcessed(logFile, file.Size())
+			if err != nil {
+				return err
+			}
+			if done {
+				continue
+			}
+		}
+
+		fmt.Println("reading log", logFile)
+		if err := readLog(logFile, 0, events); err != nil {
+			fmt.Printf("Error reading log file %s: %s\n", logFile, err)
+			return err
 		}
-		if fi.Size() < pos {
-			pos = 0
+
+		if playerStore != nil {
+			if err := playerStore.MarkFileProcessed(logFile, file.Size()); err != nil {
+				return err
+			}
 		}
-		logf.Seek(pos, os.SEEK_SET)
-		fmt.Printf("Starting read at offset %d\n", pos)
-		rdr = bufio.NewReader(logf)
 	}
 
-	return rdr, nil
+	return nil
 }
 
+// ingestNewRotatedLog reads the single .gz log that a rotation just produced
+// — the one with a ModTime after cutoff — rather than rescanning and
+// replaying every .gz in logPath the way ingestRotatedLogs does for the
+// startup backlog. That full rescan is fine once, but reusing it on every
+// later rotation means the amount of work (and, without a store, the
+// already-replayed events re-emitted) grows with the server's entire
+// history instead of staying O(1) per rotation.
 //
-// Read the log file, figure out who's on, and return a slice of users, like delicious pie.
+// It still has to list the directory, since the rotated file's name isn't
+// predictable in advance, but it only reads the content of files newer than
+// cutoff. drained is how many bytes of the just-rotated file lf.drain()
+// already emitted live, from lf.offset; since gzip doesn't change the
+// plaintext content, that many bytes are skipped at the front of whichever
+// of these files is the one latest.log was just rotated into (the one with
+// the newest ModTime), so lines already emitted don't get emitted again.
+// Any other, older candidate is read in full, as it can't overlap with
+// what's been drained live. It returns the new cutoff to use next time: the
+// ModTime of the newest file it saw, or the original cutoff if nothing new
+// has appeared yet (the gzip can still be in flight when rotation is first
+// detected).
+func ingestNewRotatedLog(logPath string, cutoff time.Time, drained int64, events chan parser.Event) (time.Time, error) {
+	files, err := ioutil.ReadDir(logPath)
+	if err != nil {
+		return cutoff, err
+	}
+
+	var candidates []os.FileInfo
+	for i := range files {
+		file := files[i]
+		if path.Ext(file.Name()) != gzipext {
+			continue
+		}
+		if !file.ModTime().After(cutoff) {
+			continue
+		}
+		candidates = append(candidates, file)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].ModTime().Before(candidates[j].ModTime())
+	})
+
+	next := cutoff
+	for i, file := range candidates {
+		if file.ModTime().After(next) {
+			next = file.ModTime()
+		}
+
+		logFile := path.Join(logPath, file.Name())
+		if playerStore != nil {
+			done, err := playerStore.IsFileProcessed(logFile, file.Size())
+			if err != nil {
+				return cutoff, err
+			}
+			if done {
+				continue
+			}
+		}
+
+		skip := int64(0)
+		if i == len(candidates)-1 {
+			skip = drained
+		}
+
+		fmt.Println("reading rotated log", logFile)
+		if err := readLog(logFile, skip, events); err != nil {
+			fmt.Printf("Error reading log file %s: %s\n", logFile, err)
+			return cutoff, err
+		}
+
+		if playerStore != nil {
+			if err := playerStore.MarkFileProcessed(logFile, file.Size()); err != nil {
+				return cutoff, err
+			}
+		}
+	}
+
+	return next, nil
+}
+
+func getLogReader(logf *os.File) (*bufio.Reader, error) {
+	gzrdr, err := gzip.NewReader(logf)
+	if err != nil {
+		return nil, err
+	}
+	return bufio.NewReader(gzrdr), nil
+}
+
+//
+// Read a (gzipped) logfile, sniffing which flavor wrote it and sending every
+// event it recognizes to events, starting skip bytes into the decompressed
+// content. Gzip doesn't change the plaintext, so skip lines up with
+// whatever byte offset the live tail already drained out of this file
+// before it was rotated; pass 0 to read the whole thing.
 //
-var datere, logonre, logoutre *regexp.Regexp
+var datere *regexp.Regexp
 
-func readLog(logfile string, conch chan mcuser, disch chan mcuser) (err error) {
-	// open the log file and jump to our current location, then we'll scan it
-	// one line at a time.
+func init() {
+	datere = regexp.MustCompile(`(\d+-\d+-\d+)`)
+}
+
+func readLog(logfile string, skip int64, events chan parser.Event) (err error) {
 	logf, err := os.Open(logfile)
 	if err != nil {
 		return err
 	}
 	defer logf.Close()
 
-	// Is this is gzip file?
-	rdr, err := getLogReader(logfile, logf)
+	lines, err := sniffGzipLines(logf, sniffLineCount)
 	if err != nil {
 		return err
 	}
+	p := parser.Detect(lines)
 
-	// The first time around, compile the regular expressions.
-	if logonre == nil {
-		datere = regexp.MustCompile(`(\d+-\d+-\d+)`)
-		logonre = regexp.MustCompile(`^\[([0-9:]+)\] \[.*\]: (\S+)\[.*\] logged in with entity id`)
-		logoutre = regexp.MustCompile(`^\[([0-9:]+)\] \[.*\]: (\S+) lost connection:`)
+	if _, err := logf.Seek(0, os.SEEK_SET); err != nil {
+		return err
 	}
-
-	// Parse the date from the log file name. This will fail for latest.log, for that
-	// use today's date
-	date := time.Now().Format("2006-01-02")
-	matches := datere.FindStringSubmatch(logfile)
-	if matches != nil {
-		date = matches[1]
-	} else {
-		var info os.FileInfo
-		info, err = os.Stat(logfile)
-		if err == nil {
-			date = info.ModTime().Format("2006-01-02")
+	rdr, err := getLogReader(logf)
+	if err != nil {
+		return err
+	}
+	if skip > 0 {
+		if _, err := io.CopyN(ioutil.Discard, rdr, skip); err != nil && err != io.EOF {
+			return err
 		}
 	}
 
-	for err == nil {
+	date := dateForLog(logfile)
+
+	for {
 		var line string
 		line, err = rdr.ReadString('\n')
-		if matches := logonre.FindStringSubmatch(line); matches != nil {
-			// log.Printf("User %s logged in at %s %s\n", matches[2], date, matches[1])
-			since, _ := parseSince(date + " " + matches[1])
-			conch <- mcuser{matches[2], since}
-		} else if matches := logoutre.FindStringSubmatch(line); matches != nil {
-			// log.Printf("User %s logged out at %s %s\n", matches[2], date, matches[1])
-			since, _ := parseSince(date + " " + matches[1])
-			disch <- mcuser{matches[2], since}
+		emitLine(p, line, date, events)
+		if err != nil {
+			break
 		}
 	}
 
-	err = nil
+	return nil
+}
 
-	// where are we?
-	if path.Ext(logfile) != gzipext {
-		pos, err = logf.Seek(0, os.SEEK_CUR)
+// sniffGzipLines decompresses logf from the start and returns its first n
+// lines, for Detect to sniff. logf is left at an unspecified position;
+// callers that go on to actually read the file must Seek back to 0 first.
+func sniffGzipLines(logf *os.File, n int) ([]string, error) {
+	rdr, err := getLogReader(logf)
+	if err != nil {
+		return nil, err
 	}
 
-	return err
+	var lines []string
+	s := bufio.NewScanner(rdr)
+	for len(lines) < n && s.Scan() {
+		lines = append(lines, s.Text())
+	}
+	return lines, nil
 }
 
 //
-// Parse the time string from a Minecraft logfile into a Time value.
+// Parse a date (from a logfile's name) and a clock reading (from one of its
+// lines) into a single Time value.
 //
-func parseSince(since string) (time.Time, error) {
+func combineDate(date string, clock time.Time) (time.Time, error) {
 	// We need to add the local time zone to the string we're parsing, or else
 	// the parser will assume it's UTC.
 	zone, _ := time.Now().Zone()
-	since = fmt.Sprintf("%s %s", since, zone)
+	since := fmt.Sprintf("%s %02d:%02d:%02d %s", date, clock.Hour(), clock.Minute(), clock.Second(), zone)
 	ts, err := time.Parse("2006-01-02 15:04:05 MST", since)
 
 	return ts, err
@@ -0,0 +1,54 @@
+//
+// Copyright 2012-2014 Geoff Hickey <trapgate@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package parser
+
+// Detect sniffs a handful of lines from the start of a log (a few hundred
+// is plenty) and returns the Parser that best matches them. Forge and
+// Legacy have prefixes no other flavor uses, so a single hit is enough to
+// pick them; Vanilla and Spigot share a prefix and are told apart by which
+// style of join/leave line shows up more often. Vanilla is the fallback
+// when nothing recognizable turns up at all, since it's the format the
+// rest of this package was originally written against.
+func Detect(lines []string) Parser {
+	var vanillaHits, spigotHits int
+
+	for _, line := range lines {
+		m := forgeLine.FindStringSubmatch(line)
+		if m != nil {
+			return Forge{}
+		}
+		if legacyLine.MatchString(line) {
+			return Legacy{}
+		}
+
+		m = bracketLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		switch {
+		case joinedGame.MatchString(m[2]), leftGame.MatchString(m[2]):
+			spigotHits++
+		case vanillaLogon.MatchString(m[2]), vanillaLogout.MatchString(m[2]):
+			vanillaHits++
+		}
+	}
+
+	if spigotHits > vanillaHits {
+		return Spigot{}
+	}
+	return Vanilla{}
+}
@@ -0,0 +1,53 @@
+//
+// Copyright 2012-2014 Geoff Hickey <trapgate@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package parser
+
+import "regexp"
+
+// Forge parses the log4j-based format Forge servers have used since they
+// switched off the plain vanilla logger: "[ddMMMyyyy HH:mm:ss.SSS]
+// [thread/LEVEL] [logger/]:" with an extra bracketed logger tag vanilla and
+// Spigot don't have. Login/logout and event wording otherwise match
+// vanilla, since Forge doesn't change that part of the server.
+type Forge struct{}
+
+var (
+	forgeLine   = regexp.MustCompile(`^\[\d{1,2}\w{3}\d{4} ([0-9:]+)\.\d+\] \[[^\]]+\] \[[^\]]+\]: (.*)$`)
+	forgeLogon  = regexp.MustCompile(`^(\S+)\[.*\] logged in with entity id`)
+	forgeLogout = regexp.MustCompile(`^(\S+) lost connection:`)
+)
+
+// Parse implements Parser.
+func (Forge) Parse(line string) (Event, bool) {
+	m := forgeLine.FindStringSubmatch(line)
+	if m == nil {
+		return Event{}, false
+	}
+	clock, ok := parseClock(m[1])
+	if !ok {
+		return Event{}, false
+	}
+	body := m[2]
+
+	switch {
+	case forgeLogon.MatchString(body):
+		return Event{Kind: Login, Name: forgeLogon.FindStringSubmatch(body)[1], Time: clock, Line: line}, true
+	case forgeLogout.MatchString(body):
+		return Event{Kind: Logout, Name: forgeLogout.FindStringSubmatch(body)[1], Time: clock, Line: line}, true
+	}
+	return parseCommonBody(clock, body, line)
+}
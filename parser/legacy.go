@@ -0,0 +1,51 @@
+//
+// Copyright 2012-2014 Geoff Hickey <trapgate@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package parser
+
+import "regexp"
+
+// Legacy parses the log format used by vanilla servers before 1.7: a full
+// "yyyy-MM-dd HH:mm:ss [LEVEL]" prefix with no thread name, and achievements
+// instead of advancements.
+type Legacy struct{}
+
+var (
+	legacyLine   = regexp.MustCompile(`^\d{4}-\d{2}-\d{2} ([0-9:]+) \[[^\]]+\] (.*)$`)
+	legacyLogon  = regexp.MustCompile(`^(\S+) \[.*\] logged in with entity id`)
+	legacyLogout = regexp.MustCompile(`^(\S+) lost connection:`)
+)
+
+// Parse implements Parser.
+func (Legacy) Parse(line string) (Event, bool) {
+	m := legacyLine.FindStringSubmatch(line)
+	if m == nil {
+		return Event{}, false
+	}
+	clock, ok := parseClock(m[1])
+	if !ok {
+		return Event{}, false
+	}
+	body := m[2]
+
+	switch {
+	case legacyLogon.MatchString(body):
+		return Event{Kind: Login, Name: legacyLogon.FindStringSubmatch(body)[1], Time: clock, Line: line}, true
+	case legacyLogout.MatchString(body):
+		return Event{Kind: Logout, Name: legacyLogout.FindStringSubmatch(body)[1], Time: clock, Line: line}, true
+	}
+	return parseCommonBody(clock, body, line)
+}
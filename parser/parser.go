@@ -0,0 +1,104 @@
+//
+// Copyright 2012-2014 Geoff Hickey <trapgate@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package parser turns raw Minecraft server log lines into typed Events.
+// Different server flavors (and different versions of vanilla) format their
+// logs differently, so rather than one hardcoded set of regexes, each
+// flavor gets its own Parser implementation, and Detect picks the right one
+// by sniffing a handful of lines from the log.
+package parser
+
+import "time"
+
+// parseClock parses a bare "15:04:05" clock reading into a Time with no
+// date component (year 0, month January, day 1). Every Parser extracts its
+// line's clock this way, even when the line's own timestamp also carries a
+// date (legacy and Forge logs do); callers combine the result with the date
+// they already know from the log's filename or position, per Event.Time.
+func parseClock(s string) (time.Time, bool) {
+	t, err := time.Parse("15:04:05", s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Kind identifies what sort of thing happened.
+type Kind int
+
+const (
+	// Login is a player connecting.
+	Login Kind = iota
+	// Logout is a player disconnecting.
+	Logout
+	// Chat is a player sending a chat message.
+	Chat
+	// Death is a player (or their pet, or their boat...) dying.
+	Death
+	// Advancement is a player completing an advancement (or, on older
+	// servers, an achievement).
+	Advancement
+	// ServerStart is the server finishing startup and accepting connections.
+	ServerStart
+	// ServerStop is the server beginning shutdown.
+	ServerStop
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Login:
+		return "login"
+	case Logout:
+		return "logout"
+	case Chat:
+		return "chat"
+	case Death:
+		return "death"
+	case Advancement:
+		return "advancement"
+	case ServerStart:
+		return "server_start"
+	case ServerStop:
+		return "server_stop"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is what a Parser produces from a single log line.
+type Event struct {
+	Kind Kind
+	// Name is the player's name, for Login, Logout, Chat, Death and
+	// Advancement; it's empty for ServerStart/ServerStop.
+	Name string
+	// Time is the wall-clock time of the line. It has no date in it (server
+	// logs only timestamp to the second within a day), so callers combine
+	// it with the date they already know about from the log's filename or
+	// position.
+	Time time.Time
+	// Message holds the chat text, death message, or advancement/achievement
+	// name; it's empty for Login, Logout, ServerStart and ServerStop.
+	Message string
+	// Line is the raw line this event was parsed from.
+	Line string
+}
+
+// Parser turns a single log line into an Event. It returns ok == false for
+// any line it doesn't recognize (the vast majority of lines in a typical
+// log: world saves, chunk loading, plugin chatter, etc).
+type Parser interface {
+	Parse(line string) (Event, bool)
+}
@@ -0,0 +1,161 @@
+//
+// Copyright 2012-2014 Geoff Hickey <trapgate@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package parser
+
+import (
+	"bufio"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// wantEvent is the subset of Event we care about checking per fixture line;
+// Line and the zero Time are left out since they're mechanical.
+type wantEvent struct {
+	kind Kind
+	name string
+}
+
+var fixtures = []struct {
+	file   string
+	want   Parser
+	events []wantEvent
+}{
+	{
+		file: "testdata/vanilla.log",
+		want: Vanilla{},
+		events: []wantEvent{
+			{ServerStart, ""},
+			{Login, "Steve"},
+			{Chat, "Steve"},
+			{Death, "Steve"},
+			{Advancement, "Steve"},
+			{Logout, "Steve"},
+			{ServerStop, ""},
+		},
+	},
+	{
+		file: "testdata/legacy.log",
+		want: Legacy{},
+		events: []wantEvent{
+			{ServerStart, ""},
+			{Login, "Steve"},
+			{Chat, "Steve"},
+			{Death, "Steve"},
+			{Advancement, "Steve"},
+			{Logout, "Steve"},
+			{ServerStop, ""},
+		},
+	},
+	{
+		file: "testdata/spigot.log",
+		want: Spigot{},
+		events: []wantEvent{
+			{ServerStart, ""},
+			{Login, "Steve"},
+			{Chat, "Steve"},
+			{Death, "Steve"},
+			{Advancement, "Steve"},
+			{Logout, "Steve"},
+			{ServerStop, ""},
+		},
+	},
+	{
+		file: "testdata/forge.log",
+		want: Forge{},
+		events: []wantEvent{
+			{ServerStart, ""},
+			{Login, "Steve"},
+			{Chat, "Steve"},
+			{Death, "Steve"},
+			{Advancement, "Steve"},
+			{Logout, "Steve"},
+			{ServerStop, ""},
+		},
+	},
+}
+
+func readLines(t *testing.T, file string) []string {
+	t.Helper()
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatalf("opening %s: %s", file, err)
+	}
+	defer f.Close()
+
+	var lines []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+	if err := s.Err(); err != nil {
+		t.Fatalf("reading %s: %s", file, err)
+	}
+	return lines
+}
+
+func TestDetect(t *testing.T) {
+	for _, f := range fixtures {
+		t.Run(f.file, func(t *testing.T) {
+			lines := readLines(t, f.file)
+			got := Detect(lines)
+			if reflect.TypeOf(got) != reflect.TypeOf(f.want) {
+				t.Errorf("Detect(%s) = %T, want %T", f.file, got, f.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, f := range fixtures {
+		t.Run(f.file, func(t *testing.T) {
+			lines := readLines(t, f.file)
+			p := Detect(lines)
+
+			var got []wantEvent
+			for _, line := range lines {
+				ev, ok := p.Parse(line)
+				if !ok {
+					continue
+				}
+				got = append(got, wantEvent{ev.Kind, ev.Name})
+			}
+
+			if !reflect.DeepEqual(got, f.events) {
+				t.Errorf("Parse(%s) events = %+v, want %+v", f.file, got, f.events)
+			}
+		})
+	}
+}
+
+func TestKindString(t *testing.T) {
+	cases := map[Kind]string{
+		Login:       "login",
+		Logout:      "logout",
+		Chat:        "chat",
+		Death:       "death",
+		Advancement: "advancement",
+		ServerStart: "server_start",
+		ServerStop:  "server_stop",
+		Kind(99):    "unknown",
+	}
+	for k, want := range cases {
+		if got := k.String(); got != want {
+			t.Errorf("Kind(%d).String() = %q, want %q", k, got, want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+//
+// Copyright 2012-2014 Geoff Hickey <trapgate@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package parser
+
+import "regexp"
+
+// Spigot parses the log format used by Spigot and Paper servers. The
+// bracket prefix and most event lines are identical to Vanilla, but
+// Bukkit-derived servers don't print the "logged in with entity id" /
+// "lost connection" lines to console, only the "joined the game" / "left
+// the game" broadcast that's also shown to players.
+type Spigot struct{}
+
+var (
+	joinedGame = regexp.MustCompile(`^(\S+) joined the game$`)
+	leftGame   = regexp.MustCompile(`^(\S+) left the game$`)
+)
+
+// Parse implements Parser.
+func (Spigot) Parse(line string) (Event, bool) {
+	m := bracketLine.FindStringSubmatch(line)
+	if m == nil {
+		return Event{}, false
+	}
+	clock, ok := parseClock(m[1])
+	if !ok {
+		return Event{}, false
+	}
+	body := m[2]
+
+	switch {
+	case joinedGame.MatchString(body):
+		return Event{Kind: Login, Name: joinedGame.FindStringSubmatch(body)[1], Time: clock, Line: line}, true
+	case leftGame.MatchString(body):
+		return Event{Kind: Logout, Name: leftGame.FindStringSubmatch(body)[1], Time: clock, Line: line}, true
+	}
+	return parseCommonBody(clock, body, line)
+}
@@ -0,0 +1,92 @@
+//
+// Copyright 2012-2014 Geoff Hickey <trapgate@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package parser
+
+import (
+	"regexp"
+	"time"
+)
+
+// Vanilla parses the log format used by stock vanilla servers from 1.7
+// through the last pre-log4j releases: a bare "[HH:MM:SS] [thread/LEVEL]:"
+// prefix on every line, with no date and no mod-loader tag. Logins are
+// recognized by the "logged in with entity id" line vanilla prints, which
+// Bukkit-derived servers suppress; see Spigot for those.
+type Vanilla struct{}
+
+var (
+	bracketLine   = regexp.MustCompile(`^\[([0-9:]+)\] \[[^\]]+\]: (.*)$`)
+	vanillaLogon  = regexp.MustCompile(`^(\S+)\[.*\] logged in with entity id`)
+	vanillaLogout = regexp.MustCompile(`^(\S+) lost connection:`)
+)
+
+// Parse implements Parser.
+func (Vanilla) Parse(line string) (Event, bool) {
+	m := bracketLine.FindStringSubmatch(line)
+	if m == nil {
+		return Event{}, false
+	}
+	clock, ok := parseClock(m[1])
+	if !ok {
+		return Event{}, false
+	}
+	body := m[2]
+
+	switch {
+	case vanillaLogon.MatchString(body):
+		return Event{Kind: Login, Name: vanillaLogon.FindStringSubmatch(body)[1], Time: clock, Line: line}, true
+	case vanillaLogout.MatchString(body):
+		return Event{Kind: Logout, Name: vanillaLogout.FindStringSubmatch(body)[1], Time: clock, Line: line}, true
+	}
+	return parseCommonBody(clock, body, line)
+}
+
+// parseCommonBody matches the part of a bracket-prefixed line after
+// "[HH:MM:SS] [thread/LEVEL]:" against the events that vanilla, Spigot and
+// Forge all format identically: chat, deaths, advancements, and server
+// start/stop. Login/logout are handled by each flavor's own Parse, since
+// that's where they actually differ.
+func parseCommonBody(clock time.Time, body, line string) (Event, bool) {
+	switch {
+	case chatRe.MatchString(body):
+		// Checked first: a chat message's own text can start with wording
+		// that deathRe would otherwise mistake for a death ("<Steve> fell
+		// asleep at the keyboard"), since deathRe only anchors on a bare
+		// name, not the "<name>" chat prefix.
+		m := chatRe.FindStringSubmatch(body)
+		return Event{Kind: Chat, Name: m[1], Message: m[2], Time: clock, Line: line}, true
+	case advanceRe.MatchString(body):
+		m := advanceRe.FindStringSubmatch(body)
+		return Event{Kind: Advancement, Name: m[1], Message: m[2], Time: clock, Line: line}, true
+	case deathRe.MatchString(body):
+		m := deathRe.FindStringSubmatch(body)
+		return Event{Kind: Death, Name: m[1], Message: body, Time: clock, Line: line}, true
+	case startRe.MatchString(body):
+		return Event{Kind: ServerStart, Time: clock, Line: line}, true
+	case stopRe.MatchString(body):
+		return Event{Kind: ServerStop, Time: clock, Line: line}, true
+	}
+	return Event{}, false
+}
+
+var (
+	chatRe    = regexp.MustCompile(`^<(\S+)> (.*)$`)
+	deathRe   = regexp.MustCompile(`^(\S+) (?:died|was .*|fell .*|drowned.*|burned .*|blew up.*|went up in flames.*|walked into .*|was slain by .*|was shot by .*|was blown up by .*|tried to swim in lava.*|suffocated.*|withered away.*)$`)
+	advanceRe = regexp.MustCompile(`^(\S+) has (?:made the advancement|completed the challenge|reached the goal|just earned the achievement) \[(.*)\]$`)
+	startRe   = regexp.MustCompile(`^Done \(.*\)! For help, type "help" or "\?"$`)
+	stopRe    = regexp.MustCompile(`^Stopping( the)? server$`)
+)
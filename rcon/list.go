@@ -0,0 +1,31 @@
+package rcon
+
+import (
+	"regexp"
+	"strings"
+)
+
+// listRe matches the vanilla "list" command reply:
+//   There are 2 of a max of 20 players online: alice, bob
+// Older server versions omit the second "of" ("a max 20"), so it's optional.
+var listRe = regexp.MustCompile(`^There are (\d+) of a max(?: of)? (\d+) players online:\s*(.*)$`)
+
+// ParseList parses the reply to the "list" command into the set of player
+// names currently online. It returns ok == false if reply didn't look like a
+// list reply at all.
+func ParseList(reply string) (names []string, ok bool) {
+	matches := listRe.FindStringSubmatch(strings.TrimSpace(reply))
+	if matches == nil {
+		return nil, false
+	}
+
+	rest := strings.TrimSpace(matches[3])
+	if rest == "" {
+		return []string{}, true
+	}
+
+	for _, name := range strings.Split(rest, ",") {
+		names = append(names, strings.TrimSpace(name))
+	}
+	return names, true
+}
@@ -0,0 +1,178 @@
+//
+// Copyright 2012-2014 Geoff Hickey <trapgate@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//
+// Package rcon implements a small client for the Source RCON protocol, which
+// is what the Minecraft server speaks when rcon is enabled in
+// server.properties. It's used to issue commands like "list" and get back
+// the authoritative answer, rather than relying solely on scraping the log.
+//
+package rcon
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Packet types, per the Source RCON protocol spec.
+const (
+	typeAuth         int32 = 3
+	typeAuthResponse int32 = 2
+	typeCommand      int32 = 2
+	typeResponse     int32 = 0
+)
+
+// maxPacketSize is larger than any response the vanilla server sends; it's
+// just a sanity check against a malformed length field.
+const maxPacketSize = 1 << 20
+
+// ErrAuthFailed is returned by Dial when the server rejects our password.
+var ErrAuthFailed = errors.New("rcon: authentication failed")
+
+// Client is a connection to a Minecraft server's rcon port. It's safe to call
+// SendCommand from multiple goroutines; requests are serialized internally
+// because the protocol has no way to match out-of-order responses other than
+// by request id, and we don't bother pipelining.
+type Client struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	rdr    *bufio.Reader
+	nextID int32
+}
+
+// Dial connects to addr (host:port) and authenticates with password. The
+// returned Client should be closed with Close when no longer needed.
+func Dial(addr, password string) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{conn: conn, rdr: bufio.NewReader(conn), nextID: 1}
+	id, err := c.send(typeAuth, password)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// The auth response packet has an empty body and echoes our request id,
+	// or -1 if the password was wrong.
+	respID, _, err := c.readPacket()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if respID == -1 || respID != id {
+		conn.Close()
+		return nil, ErrAuthFailed
+	}
+
+	return c, nil
+}
+
+// Close closes the underlying connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// SendCommand sends cmd (e.g. "list", "say hi") and returns the server's
+// response body.
+func (c *Client) SendCommand(cmd string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.send(typeCommand, cmd)
+	if err != nil {
+		return "", err
+	}
+
+	respID, body, err := c.readPacket()
+	if err != nil {
+		return "", err
+	}
+	if respID != id {
+		return "", fmt.Errorf("rcon: response id %d doesn't match request id %d", respID, id)
+	}
+
+	return body, nil
+}
+
+// send writes a single request packet and returns the request id it used.
+func (c *Client) send(ptype int32, body string) (int32, error) {
+	id := c.nextID
+	c.nextID++
+
+	// payload: id (4) + type (4) + body + nul + nul
+	payload := make([]byte, 0, 8+len(body)+2)
+	payload = appendInt32(payload, id)
+	payload = appendInt32(payload, ptype)
+	payload = append(payload, body...)
+	payload = append(payload, 0, 0)
+
+	pkt := make([]byte, 0, 4+len(payload))
+	pkt = appendInt32(pkt, int32(len(payload)))
+	pkt = append(pkt, payload...)
+
+	_, err := c.conn.Write(pkt)
+	return id, err
+}
+
+// readPacket reads one length-prefixed packet and returns its request id and
+// body, with the trailing nul-nul stripped.
+func (c *Client) readPacket() (int32, string, error) {
+	var lenBuf [4]byte
+	if _, err := fillBuffer(c.rdr, lenBuf[:]); err != nil {
+		return 0, "", err
+	}
+	length := int32(binary.LittleEndian.Uint32(lenBuf[:]))
+	if length < 10 || int(length) > maxPacketSize {
+		return 0, "", fmt.Errorf("rcon: bad packet length %d", length)
+	}
+
+	buf := make([]byte, length)
+	if _, err := fillBuffer(c.rdr, buf); err != nil {
+		return 0, "", err
+	}
+
+	id := int32(binary.LittleEndian.Uint32(buf[0:4]))
+	// buf[4:8] is the packet type, which we don't need here.
+	body := buf[8 : len(buf)-2]
+
+	return id, string(body), nil
+}
+
+func fillBuffer(rdr *bufio.Reader, buf []byte) (int, error) {
+	total := 0
+	for total < len(buf) {
+		n, err := rdr.Read(buf[total:])
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func appendInt32(buf []byte, v int32) []byte {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(v))
+	return append(buf, b[:]...)
+}
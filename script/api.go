@@ -0,0 +1,101 @@
+package script
+
+import (
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// handlersKey is the name of the Lua global (invisible to the script author)
+// that event.on() populates: a table from event kind to handler function.
+const handlersKey = "__mcwho_handlers"
+
+// registerAPI installs the event and mc tables into a freshly created Lua
+// state, before the script itself is run.
+func registerAPI(L *lua.LState, e *Engine) {
+	L.SetGlobal(handlersKey, L.NewTable())
+
+	eventMod := L.NewTable()
+	L.SetFuncs(eventMod, map[string]lua.LGFunction{
+		"on": func(L *lua.LState) int {
+			kind := L.CheckString(1)
+			fn := L.CheckFunction(2)
+			handlers := L.GetGlobal(handlersKey).(*lua.LTable)
+			handlers.RawSetString(kind, fn)
+			return 0
+		},
+	})
+	L.SetGlobal("event", eventMod)
+
+	mcMod := L.NewTable()
+	L.SetFuncs(mcMod, map[string]lua.LGFunction{
+		"say": func(L *lua.LState) int {
+			msg := L.CheckString(1)
+			return pushCommandResult(L, e, "say "+msg)
+		},
+		"msg": func(L *lua.LState) int {
+			player := L.CheckString(1)
+			msg := L.CheckString(2)
+			return pushCommandResult(L, e, "tell "+player+" "+msg)
+		},
+		"kick": func(L *lua.LState) int {
+			player := L.CheckString(1)
+			reason := L.OptString(2, "")
+			cmd := "kick " + player
+			if reason != "" {
+				cmd += " " + reason
+			}
+			return pushCommandResult(L, e, cmd)
+		},
+		"players": func(L *lua.LState) int {
+			tbl := L.NewTable()
+			if e.players != nil {
+				for _, name := range e.players.Players() {
+					tbl.Append(lua.LString(name))
+				}
+			}
+			L.Push(tbl)
+			return 1
+		},
+		"playtime": func(L *lua.LState) int {
+			name := L.CheckString(1)
+			var secs float64
+			if e.players != nil {
+				secs = e.players.Playtime(name).Seconds()
+			}
+			L.Push(lua.LNumber(secs))
+			return 1
+		},
+	})
+	L.SetGlobal("mc", mcMod)
+}
+
+// pushCommandResult runs cmd over rcon (if configured) and leaves its string
+// reply, or an error, on the Lua stack in the usual (result, err) fashion.
+func pushCommandResult(L *lua.LState, e *Engine, cmd string) int {
+	if e.cmdr == nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("rcon is not configured"))
+		return 2
+	}
+
+	reply, err := e.cmdr.SendCommand(cmd)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	L.Push(lua.LString(strings.TrimSpace(reply)))
+	return 1
+}
+
+// eventTable builds the ctx table passed to a handler.
+func eventTable(L *lua.LState, ev Event) *lua.LTable {
+	tbl := L.NewTable()
+	tbl.RawSetString("name", lua.LString(ev.Name))
+	tbl.RawSetString("time", lua.LNumber(ev.Time.Unix()))
+	tbl.RawSetString("line", lua.LString(ev.Line))
+	tbl.RawSetString("extra", lua.LString(ev.Extra))
+	return tbl
+}
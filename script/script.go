@@ -0,0 +1,250 @@
+//
+// Copyright 2012-2014 Geoff Hickey <trapgate@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//
+// Package script lets admins drop Lua files into a directory and have them
+// react to server events (logins, logouts, chat, deaths, advancements)
+// without recompiling mcwho. It uses gopher-lua, so run
+// 'go get github.com/yuin/gopher-lua' before compiling.
+//
+package script
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Commander is the subset of the rcon client a script needs to talk back to
+// the server. It's an interface so the script package doesn't have to import
+// the rcon package or know how mcwho is wired together.
+type Commander interface {
+	SendCommand(cmd string) (string, error)
+}
+
+// PlayerInfo answers the questions scripts ask about who's on and how long
+// they've played.
+type PlayerInfo interface {
+	// Players returns the names of everyone currently online.
+	Players() []string
+	// Playtime returns how long name has been playing today. It returns 0
+	// if name isn't currently online.
+	Playtime(name string) time.Duration
+}
+
+// Event is the context table passed to every handler, as `ctx` in Lua.
+type Event struct {
+	Kind string // "login", "logout", "chat", "death", "advancement"
+	Name string // player name
+	Time time.Time
+	Line string // the raw log line that triggered this event
+	// Extra holds event-specific fields, e.g. the chat message text.
+	Extra string
+}
+
+// Timeout bounds how long a single handler invocation may run before it's
+// interrupted. Scripts that misbehave shouldn't be able to wedge the whole
+// process.
+const Timeout = 2 * time.Second
+
+// jobQueue is how many pending events a single script's worker will buffer
+// before Dispatch starts dropping events for it. A script that's fallen this
+// far behind is stuck (see Timeout), not just slow.
+const jobQueue = 32
+
+// scriptWorker owns one script's lua.LState and the goroutine that's the
+// only thing ever allowed to touch it, so a handler that runs long (or
+// forever) only backs up that script's own queue instead of the others or
+// the caller of Dispatch.
+type scriptWorker struct {
+	L    *lua.LState
+	jobs chan Event
+	quit chan struct{}
+}
+
+// Engine loads and runs the Lua scripts in a directory, dispatching events to
+// each one. Each script gets its own lua.LState and worker goroutine, so a
+// bug or infinite loop in one script can't corrupt another's globals or
+// block the rest of the system.
+type Engine struct {
+	dir     string
+	cmdr    Commander
+	players PlayerInfo
+
+	mu      sync.Mutex
+	scripts map[string]*scriptWorker // keyed by path
+}
+
+// NewEngine loads every *.lua file in dir. cmdr and players back the mc.*
+// API scripts can call; either may be nil if that functionality isn't wired
+// up yet.
+func NewEngine(dir string, cmdr Commander, players PlayerInfo) (*Engine, error) {
+	e := &Engine{
+		dir:     dir,
+		cmdr:    cmdr,
+		players: players,
+		scripts: make(map[string]*scriptWorker),
+	}
+
+	if err := e.loadAll(); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// loadAll (re)loads every script in the directory, replacing any state that
+// was already loaded for a given path.
+func (e *Engine) loadAll() error {
+	matches, err := filepath.Glob(filepath.Join(e.dir, "*.lua"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range matches {
+		if err := e.Load(path); err != nil {
+			log.Printf("script: failed to load %s: %s", path, err)
+		}
+	}
+	return nil
+}
+
+// Load (re)loads a single script file. It's exported so a caller with its
+// own fsnotify watcher on the scripts directory can hot-reload individual
+// files as they change.
+func (e *Engine) Load(path string) error {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	L := lua.NewState()
+	registerAPI(L, e)
+
+	if err := L.DoString(string(src)); err != nil {
+		L.Close()
+		return fmt.Errorf("script: error running %s: %w", path, err)
+	}
+
+	w := &scriptWorker{
+		L:    L,
+		jobs: make(chan Event, jobQueue),
+		quit: make(chan struct{}),
+	}
+	go e.run(w)
+
+	e.mu.Lock()
+	if old, ok := e.scripts[path]; ok {
+		close(old.quit)
+	}
+	e.scripts[path] = w
+	e.mu.Unlock()
+
+	log.Printf("script: loaded %s", path)
+	return nil
+}
+
+// Unload removes a script, e.g. because its file was deleted.
+func (e *Engine) Unload(path string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if w, ok := e.scripts[path]; ok {
+		close(w.quit)
+		delete(e.scripts, path)
+	}
+}
+
+// run is a script's worker goroutine: the only thing that ever touches its
+// lua.LState, so handler calls for that script never overlap. It exits,
+// closing L, once quit is signalled by Unload/Load/Close.
+func (e *Engine) run(w *scriptWorker) {
+	defer w.L.Close()
+	for {
+		select {
+		case ev := <-w.jobs:
+			e.dispatchOne(w.L, ev)
+		case <-w.quit:
+			return
+		}
+	}
+}
+
+// Dispatch hands ev to every loaded script's worker and returns without
+// waiting for any of them to run it, so a script that's slow or wedged (see
+// Timeout) only backs up its own queue instead of blocking the caller or the
+// other scripts. If a script's queue is full it's already stuck, so the
+// event is dropped for it rather than piling up further.
+func (e *Engine) Dispatch(ev Event) {
+	e.mu.Lock()
+	workers := make([]*scriptWorker, 0, len(e.scripts))
+	for _, w := range e.scripts {
+		workers = append(workers, w)
+	}
+	e.mu.Unlock()
+
+	for _, w := range workers {
+		select {
+		case w.jobs <- ev:
+		default:
+			log.Printf("script: dropping %s event, handler is still busy", ev.Kind)
+		}
+	}
+}
+
+func (e *Engine) dispatchOne(L *lua.LState, ev Event) {
+	handlers, ok := L.GetGlobal(handlersKey).(*lua.LTable)
+	if !ok {
+		return
+	}
+	fn, ok := handlers.RawGetString(ev.Kind).(*lua.LFunction)
+	if !ok || fn == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	L.SetContext(ctx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("script: handler for %s panicked: %v", ev.Kind, r)
+		}
+	}()
+
+	if err := L.CallByParam(lua.P{
+		Fn:      fn,
+		NRet:    0,
+		Protect: true,
+	}, eventTable(L, ev)); err != nil {
+		log.Printf("script: handler for %s failed: %s", ev.Kind, err)
+	}
+}
+
+// Close shuts down every loaded script's worker and Lua state.
+func (e *Engine) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for path, w := range e.scripts {
+		close(w.quit)
+		delete(e.scripts, path)
+	}
+}
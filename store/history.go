@@ -0,0 +1,44 @@
+package store
+
+import (
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// eventKey sorts lexically in time order, so History can stop scanning as
+// soon as it passes the requested window.
+func eventKey(t time.Time) []byte {
+	return []byte(t.UTC().Format(time.RFC3339Nano))
+}
+
+func putEvent(tx *bolt.Tx, ev Event) error {
+	b := tx.Bucket(eventsBucket)
+	v, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	// Events can collide on the same key if two happen in the same
+	// nanosecond; that's acceptable for a log of this kind, and the later
+	// Put simply wins.
+	return b.Put(eventKey(ev.Time), v)
+}
+
+// History returns every recorded event at or after since, oldest first.
+func (s *Store) History(since time.Time) ([]Event, error) {
+	var events []Event
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(eventsBucket)
+		c := b.Cursor()
+		for k, v := c.Seek(eventKey(since)); k != nil; k, v = c.Next() {
+			var ev Event
+			if err := json.Unmarshal(v, &ev); err != nil {
+				return err
+			}
+			events = append(events, ev)
+		}
+		return nil
+	})
+	return events, err
+}
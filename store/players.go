@@ -0,0 +1,123 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// RecordLogin records that name logged in at t, updating first/last-seen
+// and starting a new session for playtime accounting. It's a no-op if it's
+// an exact repeat of the last login already recorded for name, so a
+// duplicate event (e.g. from the log tailer re-emitting a line across a
+// rotation) doesn't reset the session's start time out from under it.
+func (s *Store) RecordLogin(name string, t time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		stats, err := getStats(tx, name)
+		if err != nil {
+			return err
+		}
+		if stats.LastEventKind == "login" && stats.LastEventTime.Equal(t) {
+			return nil
+		}
+		if stats.FirstSeen.IsZero() {
+			stats.FirstSeen = t
+		}
+		stats.Name = name
+		stats.LastSeen = t
+		stats.Online = true
+		stats.SessionStart = t
+		stats.LastEventKind = "login"
+		stats.LastEventTime = t
+
+		if err := putStats(tx, stats); err != nil {
+			return err
+		}
+		return putEvent(tx, Event{Name: name, Kind: "login", Time: t})
+	})
+}
+
+// RecordLogout records that name logged out at t, folding the just-ended
+// session into the player's cumulative playtime. It's a no-op if it's an
+// exact repeat of the last logout already recorded for name, for the same
+// reason as RecordLogin: without this, a duplicate logout following a
+// duplicate login would double-count that session's playtime.
+func (s *Store) RecordLogout(name string, t time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		stats, err := getStats(tx, name)
+		if err != nil {
+			return err
+		}
+		if stats.LastEventKind == "logout" && stats.LastEventTime.Equal(t) {
+			return nil
+		}
+		if !stats.SessionStart.IsZero() && t.After(stats.SessionStart) {
+			stats.TotalPlaytime += t.Sub(stats.SessionStart)
+		}
+		stats.Name = name
+		stats.LastSeen = t
+		stats.Online = false
+		stats.SessionStart = time.Time{}
+		stats.LastEventKind = "logout"
+		stats.LastEventTime = t
+
+		if err := putStats(tx, stats); err != nil {
+			return err
+		}
+		return putEvent(tx, Event{Name: name, Kind: "logout", Time: t})
+	})
+}
+
+// PlayerStats returns everything we know about name. If we've never seen
+// them, it returns a zero Stats and no error.
+func (s *Store) PlayerStats(name string) (Stats, error) {
+	var stats Stats
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		stats, err = getStats(tx, name)
+		return err
+	})
+	return stats, err
+}
+
+// AllPlayers returns lifetime stats for every player we've ever seen.
+func (s *Store) AllPlayers() ([]Stats, error) {
+	var all []Stats
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(playersBucket)
+		return b.ForEach(func(k, v []byte) error {
+			var stats Stats
+			if err := json.Unmarshal(v, &stats); err != nil {
+				return err
+			}
+			all = append(all, stats)
+			return nil
+		})
+	})
+	return all, err
+}
+
+func getStats(tx *bolt.Tx, name string) (Stats, error) {
+	b := tx.Bucket(playersBucket)
+	v := b.Get([]byte(name))
+	if v == nil {
+		return Stats{Name: name}, nil
+	}
+
+	var stats Stats
+	if err := json.Unmarshal(v, &stats); err != nil {
+		return Stats{}, fmt.Errorf("store: corrupt stats for %s: %w", name, err)
+	}
+	return stats, nil
+}
+
+func putStats(tx *bolt.Tx, stats Stats) error {
+	b := tx.Bucket(playersBucket)
+	v, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(stats.Name), v)
+}
@@ -0,0 +1,37 @@
+package store
+
+import (
+	"encoding/binary"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// IsFileProcessed reports whether a rotated logfile (named by path, which
+// includes enough of the directory to disambiguate across log rotations)
+// has already been replayed into the store at the given size. Rotated .gz
+// logs never change once gzip has finished, so filename+size is a reliable
+// idempotency key: if we've already recorded a file of this size under this
+// name, replaying it again would just double-count every login/logout.
+func (s *Store) IsFileProcessed(name string, size int64) (bool, error) {
+	var done bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(filesBucket)
+		v := b.Get([]byte(name))
+		if v == nil {
+			return nil
+		}
+		done = int64(binary.BigEndian.Uint64(v)) == size
+		return nil
+	})
+	return done, err
+}
+
+// MarkFileProcessed records that name has been fully replayed at size bytes.
+func (s *Store) MarkFileProcessed(name string, size int64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(filesBucket)
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], uint64(size))
+		return b.Put([]byte(name), buf[:])
+	})
+}
@@ -0,0 +1,94 @@
+//
+// Copyright 2012-2014 Geoff Hickey <trapgate@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//
+// Package store persists player login/logout history to disk, so mcwho can
+// answer "how long has alice played, total?" across restarts instead of
+// forgetting everything the moment the process exits. It's backed by
+// bbolt, so run 'go get go.etcd.io/bbolt' before compiling.
+//
+package store
+
+import (
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	playersBucket = []byte("players")
+	eventsBucket  = []byte("events")
+	filesBucket   = []byte("files")
+)
+
+// Store is a handle on the on-disk database. It's safe for concurrent use
+// from multiple goroutines.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens (creating if necessary) the database at path.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{playersBucket, eventsBucket, filesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the underlying database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Stats is what we know about a player across all the time we've been
+// watching the server.
+type Stats struct {
+	Name          string        `json:"name"`
+	FirstSeen     time.Time     `json:"first_seen"`
+	LastSeen      time.Time     `json:"last_seen"`
+	TotalPlaytime time.Duration `json:"total_playtime_ns"`
+	Online        bool          `json:"online"`
+	SessionStart  time.Time     `json:"session_start,omitempty"`
+	// LastEventKind and LastEventTime record the last login/logout
+	// RecordLogin/RecordLogout actually applied, so a duplicate of that
+	// same event (e.g. a line re-emitted by the log tailer across a
+	// rotation) can be told apart from a genuinely new one and ignored,
+	// rather than double-counting playtime.
+	LastEventKind string    `json:"last_event_kind,omitempty"`
+	LastEventTime time.Time `json:"last_event_time,omitempty"`
+}
+
+// Event is a single login or logout, as recorded in the history log.
+type Event struct {
+	Name string    `json:"name"`
+	Kind string    `json:"kind"` // "login" or "logout"
+	Time time.Time `json:"time"`
+}
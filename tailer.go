@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/trapgate/mcwho/parser"
+)
+
+// sniffLineCount is how many lines of a log we read to guess its flavor
+// before settling in to actually parse it. A few hundred is plenty to see
+// past the startup banner to a login or chat line.
+const sniffLineCount = 200
+
+// LogFile tracks how far we've read into latest.log across Minecraft's
+// rotate-rename-gzip dance at midnight or on restart. Unlike the old global
+// `pos` variable this used to be, it's keyed on inode rather than path:
+// Minecraft renames latest.log out from under us and starts a fresh file at
+// the same path, so comparing sizes alone can't tell a genuinely new file
+// from one that's merely shrunk. Holding the fd open also means that even
+// after the rename, we can still drain whatever was written to the old
+// file right up until the moment it happened.
+type LogFile struct {
+	path   string
+	fd     *os.File
+	inode  uint64
+	offset int64
+	parser parser.Parser
+}
+
+// openLogFile opens path fresh, starting from offset 0.
+func openLogFile(path string) (*LogFile, error) {
+	lf := &LogFile{path: path}
+	if err := lf.reopen(); err != nil {
+		return nil, err
+	}
+	return lf, nil
+}
+
+// reopen (re)opens lf.path from scratch. Used both on creation and after a
+// rotation, once the new latest.log has appeared.
+func (lf *LogFile) reopen() error {
+	fd, err := os.Open(lf.path)
+	if err != nil {
+		return err
+	}
+	if lf.fd != nil {
+		lf.fd.Close()
+	}
+	lf.fd = fd
+	lf.offset = 0
+	lf.inode = inodeOf(fd)
+	lf.parser = detectParser(fd)
+	return nil
+}
+
+// detectParser sniffs up to sniffLineCount lines from the start of fd to
+// guess which server flavor wrote it. It's called on every reopen, not just
+// once, since a rotation can just as easily be a server upgrade as a fresh
+// day's log.
+func detectParser(fd *os.File) parser.Parser {
+	var lines []string
+	s := bufio.NewScanner(fd)
+	for len(lines) < sniffLineCount && s.Scan() {
+		lines = append(lines, s.Text())
+	}
+	return parser.Detect(lines)
+}
+
+// Close closes the underlying file descriptor.
+func (lf *LogFile) Close() error {
+	return lf.fd.Close()
+}
+
+// rotated reports whether lf.path now refers to a different inode than the
+// one we have open, i.e. whether Minecraft has rotated it out from under us.
+// It also returns true if the path has been removed outright, which happens
+// briefly between the rename and the new latest.log being created.
+func (lf *LogFile) rotated() bool {
+	fi, err := os.Stat(lf.path)
+	if err != nil {
+		return true
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	return ok && st.Ino != lf.inode
+}
+
+// drain reads every complete line available in the file from lf.offset
+// onward, dispatching the events it recognizes, and advances lf.offset past
+// whatever it managed to read. An incomplete trailing line (the writer is
+// mid-write) is left for the next call.
+func (lf *LogFile) drain(events chan parser.Event) error {
+	if _, err := lf.fd.Seek(lf.offset, os.SEEK_SET); err != nil {
+		return err
+	}
+
+	rdr := bufio.NewReader(lf.fd)
+	date := dateForLog(lf.path)
+
+	for {
+		line, err := rdr.ReadString('\n')
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			emitLine(lf.parser, line, date, events)
+			lf.offset += int64(len(line))
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	return nil
+}
+
+func inodeOf(fd *os.File) uint64 {
+	fi, err := fd.Stat()
+	if err != nil {
+		return 0
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0
+	}
+	return st.Ino
+}
+
+// dateForLog guesses the calendar date a log line without its own date
+// belongs to: rotated logs carry it in their filename, latest.log doesn't
+// so we fall back to today.
+func dateForLog(logfile string) string {
+	if matches := datere.FindStringSubmatch(logfile); matches != nil {
+		return matches[1]
+	}
+	return time.Now().Format("2006-01-02")
+}
+
+// emitLine parses a single log line with p and, if it recognizes it,
+// combines the event's clock with date and sends it on events. Lines p
+// doesn't recognize (the vast majority) are silently dropped.
+func emitLine(p parser.Parser, line, date string, events chan parser.Event) {
+	ev, ok := p.Parse(strings.TrimRight(line, "\r\n"))
+	if !ok {
+		return
+	}
+	if ts, err := combineDate(date, ev.Time); err == nil {
+		ev.Time = ts
+	}
+	events <- ev
+}